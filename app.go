@@ -0,0 +1,94 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"github.com/caddyserver/caddy/v2"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(App{})
+}
+
+// App implements git app for Caddy.
+type App struct {
+	Name   string  `json:"-"`
+	Config *Config `json:"config,omitempty"`
+
+	logger    *zap.Logger
+	scheduler *scheduler
+}
+
+// CaddyModule returns the Caddy module information.
+func (App) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "git",
+		New: func() caddy.Module { return new(App) },
+	}
+}
+
+// Provision sets up the App.
+func (a *App) Provision(ctx caddy.Context) error {
+	a.logger = ctx.Logger(a)
+	if a.Config == nil {
+		a.Config = &Config{}
+	}
+	for _, repo := range a.Config.Repositories {
+		if err := repo.Provision(a.logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates the App.
+func (a *App) Validate() error {
+	for _, repo := range a.Config.Repositories {
+		if err := repo.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts the App.
+func (a *App) Start() error {
+	for _, repo := range a.Config.Repositories {
+		if err := repo.Start(); err != nil {
+			return err
+		}
+	}
+	a.scheduler = newScheduler(a.logger)
+	a.scheduler.Start(a.Config.Repositories)
+	return nil
+}
+
+// Stop stops the App.
+func (a *App) Stop() error {
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+	for _, repo := range a.Config.Repositories {
+		repo.Stop()
+	}
+	return nil
+}
+
+var (
+	_ caddy.App         = (*App)(nil)
+	_ caddy.Provisioner = (*App)(nil)
+	_ caddy.Validator   = (*App)(nil)
+)
@@ -0,0 +1,124 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// AuthConfig holds the credentials used to authenticate against a
+// remote repository. Exactly one auth method is expected to be set;
+// if several are, the first set of Agent, GithubApp, TokenFrom,
+// KeyPath, and Username is used.
+type AuthConfig struct {
+	// KeyPath/KeyPassphrase authenticate over SSH with a private key
+	// file.
+	KeyPath       string `json:"key_path,omitempty"`
+	KeyPassphrase string `json:"key_passphrase,omitempty"`
+	// Username/Password authenticate over HTTPS with basic auth.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Agent authenticates over SSH using the agent listening on
+	// SSH_AUTH_SOCK, so the private key never touches the Caddyfile or
+	// disk.
+	Agent bool `json:"agent,omitempty"`
+	// GithubApp authenticates over HTTPS using a token minted from a
+	// GitHub App's private key.
+	GithubApp *GithubAppAuth `json:"github_app,omitempty"`
+	// TokenFrom indirectly resolves an HTTPS password, one of
+	// "env:NAME", "file:/path", or "exec:/path/to/bin", so secrets
+	// never appear inline in the Caddyfile.
+	TokenFrom string `json:"token_from,omitempty"`
+}
+
+// method builds the go-git transport.AuthMethod described by the config.
+func (a *AuthConfig) method() (transport.AuthMethod, error) {
+	if a == nil {
+		return nil, nil
+	}
+	switch {
+	case a.Agent:
+		return gitssh.NewSSHAgentAuth("git")
+	case a.GithubApp != nil:
+		token, err := a.GithubApp.Token()
+		if err != nil {
+			return nil, err
+		}
+		return &githttp.BasicAuth{Username: "x-access-token", Password: token}, nil
+	case a.TokenFrom != "":
+		token, err := resolveTokenFrom(a.TokenFrom)
+		if err != nil {
+			return nil, err
+		}
+		return &githttp.BasicAuth{Username: "x-access-token", Password: token}, nil
+	case a.KeyPath != "":
+		return gitssh.NewPublicKeysFromFile("git", expandHomeDir(a.KeyPath), a.KeyPassphrase)
+	case a.Username != "":
+		return &githttp.BasicAuth{Username: a.Username, Password: a.Password}, nil
+	}
+	return nil, nil
+}
+
+// resolveTokenFrom resolves the secret referenced by a `token_from`
+// value: "env:NAME" reads an environment variable, "file:/path" reads a
+// file (trimming surrounding whitespace), and "exec:/path/to/bin" runs a
+// command and uses its trimmed stdout.
+func resolveTokenFrom(ref string) (string, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid token_from %q: expected \"env:\", \"file:\", or \"exec:\" prefix", ref)
+	}
+	scheme, value := parts[0], parts[1]
+	switch scheme {
+	case "env":
+		token, ok := os.LookupEnv(value)
+		if !ok {
+			return "", fmt.Errorf("token_from: environment variable %q not set", value)
+		}
+		return token, nil
+	case "file":
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return "", fmt.Errorf("token_from: failed to read %q: %v", value, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "exec":
+		out, err := exec.Command(value).Output()
+		if err != nil {
+			return "", fmt.Errorf("token_from: failed to run %q: %v", value, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	default:
+		return "", fmt.Errorf("invalid token_from %q: unsupported scheme %q", ref, scheme)
+	}
+}
+
+// expandHomeDir expands a leading "~" in p to the current user's home
+// directory, as used by `ssh` key paths in the Caddyfile.
+func expandHomeDir(p string) string {
+	if len(p) > 0 && p[0] == '~' {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + p[1:]
+		}
+	}
+	return p
+}
@@ -0,0 +1,349 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"github.com/caddyserver/caddy/v2/caddyconfig/httpcaddyfile"
+)
+
+func init() {
+	httpcaddyfile.RegisterGlobalOption("git", parseCaddyfileAppConfig)
+}
+
+// parseCaddyfileAppConfig sets up the "git" global option from Caddyfile
+// tokens, e.g.:
+//
+//	git {
+//	  repo <name> {
+//	    base_dir <path>
+//	    url <address>
+//	    branch <name>
+//	    force <bool>
+//	  }
+//	}
+func parseCaddyfileAppConfig(d *caddyfile.Dispenser, _ interface{}) (interface{}, error) {
+	cfg := &Config{}
+	for d.Next() {
+		for nesting := d.Nesting(); d.NextBlock(nesting); {
+			switch d.Val() {
+			case "repo":
+				repo, err := parseCaddyfileRepo(d)
+				if err != nil {
+					return nil, err
+				}
+				cfg.Repositories = append(cfg.Repositories, repo)
+			default:
+				return nil, d.Errf("unsupported %q key", d.Val())
+			}
+		}
+	}
+	return httpcaddyfile.App{
+		Name:  "git",
+		Value: caddyconfig.JSON(App{Config: cfg}, nil),
+	}, nil
+}
+
+// parseCaddyfileRepo parses a single `repo <name> { ... }` block.
+func parseCaddyfileRepo(d *caddyfile.Dispenser) (*Repository, error) {
+	args := d.RemainingArgs()
+	if len(args) != 1 {
+		return nil, d.ArgErr()
+	}
+	repo := &Repository{Name: args[0]}
+
+	for nesting := d.Nesting(); d.NextBlock(nesting); {
+		key := d.Val()
+		switch key {
+		case "base_dir":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			repo.BaseDir = args[0]
+		case "url":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			repo.Address = args[0]
+		case "branch":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			repo.Branch = args[0]
+		case "force":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			force, err := strconv.ParseBool(args[0])
+			if err != nil {
+				return nil, d.Errf("invalid %q value %q: %v", key, args[0], err)
+			}
+			repo.Force = force
+		case "depth":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			depth, err := strconv.Atoi(args[0])
+			if err != nil {
+				return nil, d.Errf("invalid %q value %q: %v", key, args[0], err)
+			}
+			repo.Depth = depth
+		case "single_branch":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			singleBranch, err := strconv.ParseBool(args[0])
+			if err != nil {
+				return nil, d.Errf("invalid %q value %q: %v", key, args[0], err)
+			}
+			repo.SingleBranch = singleBranch
+		case "sparse":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			repo.Sparse = append(repo.Sparse, args...)
+		case "schedule":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			repo.Schedule = strings.Join(args, " ")
+		case "webhook":
+			webhook, err := parseCaddyfileWebhook(d)
+			if err != nil {
+				return nil, err
+			}
+			repo.Webhooks = append(repo.Webhooks, webhook)
+		case "auth":
+			auth, err := parseCaddyfileAuth(d)
+			if err != nil {
+				return nil, err
+			}
+			repo.Auth = auth
+		case "post":
+			exec, err := parseCaddyfilePostPullExec(d)
+			if err != nil {
+				return nil, err
+			}
+			repo.PostPullExec = append(repo.PostPullExec, exec)
+		default:
+			return nil, d.Errf("unsupported %q key", key)
+		}
+	}
+	return repo, nil
+}
+
+// parseCaddyfileWebhook parses a `webhook <provider> <header> <secret>`
+// directive, optionally followed by a block with `events`, `match`, and
+// `allow` fine-tuning.
+func parseCaddyfileWebhook(d *caddyfile.Dispenser) (*Webhook, error) {
+	args, err := parseCaddyfileArgs(d, "webhook", 3)
+	if err != nil {
+		return nil, err
+	}
+	webhook := &Webhook{
+		Name:   args[0],
+		Header: args[1],
+		Secret: args[2],
+	}
+
+	for nesting := d.Nesting(); d.NextBlock(nesting); {
+		key := d.Val()
+		switch key {
+		case "events":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range args {
+				webhook.Events = append(webhook.Events, strings.Split(a, ",")...)
+			}
+		case "match":
+			args, err := parseCaddyfileArgs(d, key, 3)
+			if err != nil {
+				return nil, err
+			}
+			webhook.Match = strings.Join(args, " ")
+		case "allow":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			webhook.Allow = append(webhook.Allow, args...)
+		default:
+			return nil, d.Errf("unsupported %q key", key)
+		}
+	}
+	return webhook, nil
+}
+
+// parseCaddyfileAuth parses the `auth key <path> [passphrase <value>]`,
+// `auth username <user> password <pass>`, `auth agent`,
+// `auth github_app app_id <id> installation_id <id> private_key <path>`,
+// and `auth token_from <env:NAME|file:/path|exec:/path>` forms.
+func parseCaddyfileAuth(d *caddyfile.Dispenser) (*AuthConfig, error) {
+	args, err := parseCaddyfileArgs(d, "auth", 1)
+	if err != nil {
+		return nil, err
+	}
+	auth := &AuthConfig{}
+	switch args[0] {
+	case "key":
+		if len(args) < 2 {
+			return nil, d.Errf("malformed %q directive", "auth key")
+		}
+		auth.KeyPath = args[1]
+		if len(args) >= 4 && args[2] == "passphrase" {
+			auth.KeyPassphrase = args[3]
+		}
+	case "username":
+		if len(args) < 4 || args[2] != "password" {
+			return nil, d.Errf("malformed %q directive", "auth username")
+		}
+		auth.Username = args[1]
+		auth.Password = args[3]
+	case "agent":
+		auth.Agent = true
+	case "github_app":
+		githubApp, err := parseGithubAppArgs(d, args[1:])
+		if err != nil {
+			return nil, err
+		}
+		auth.GithubApp = githubApp
+	case "token_from":
+		if len(args) < 2 {
+			return nil, d.Errf("malformed %q directive", "auth token_from")
+		}
+		auth.TokenFrom = args[1]
+	default:
+		return nil, d.Errf("unsupported auth method %q", args[0])
+	}
+	return auth, nil
+}
+
+// parseGithubAppArgs parses the `app_id <id> installation_id <id>
+// private_key <path>` triplet that follows `auth github_app`.
+func parseGithubAppArgs(d *caddyfile.Dispenser, args []string) (*GithubAppAuth, error) {
+	githubApp := &GithubAppAuth{}
+	for i := 0; i < len(args)-1; i += 2 {
+		key, val := args[i], args[i+1]
+		switch key {
+		case "app_id":
+			id, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return nil, d.Errf("invalid app_id %q: %v", val, err)
+			}
+			githubApp.AppID = id
+		case "installation_id":
+			id, err := strconv.ParseInt(val, 10, 64)
+			if err != nil {
+				return nil, d.Errf("invalid installation_id %q: %v", val, err)
+			}
+			githubApp.InstallationID = id
+		case "private_key":
+			githubApp.PrivateKeyPath = val
+		default:
+			return nil, d.Errf("unsupported %q key", key)
+		}
+	}
+	if githubApp.AppID == 0 || githubApp.InstallationID == 0 || githubApp.PrivateKeyPath == "" {
+		return nil, d.Errf("%q requires app_id, installation_id, and private_key", "auth github_app")
+	}
+	return githubApp, nil
+}
+
+// parseCaddyfilePostPullExec parses a `post pull exec { ... }` block.
+func parseCaddyfilePostPullExec(d *caddyfile.Dispenser) (*PostPullExec, error) {
+	args, err := parseCaddyfileArgs(d, "post", 2)
+	if err != nil {
+		return nil, err
+	}
+	if args[0] != "pull" || args[1] != "exec" {
+		return nil, d.Errf("unsupported %q directive", "post")
+	}
+
+	exec := &PostPullExec{}
+	for nesting := d.Nesting(); d.NextBlock(nesting); {
+		key := d.Val()
+		switch key {
+		case "name":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.Name = args[0]
+		case "command":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.Command = args[0]
+		case "args":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.Args = args
+		case "timeout":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.Timeout = args[0]
+		case "working_dir":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.WorkingDir = args[0]
+		case "env":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.Env = append(exec.Env, strings.Join(args, " "))
+		case "on_error":
+			args, err := parseCaddyfileArgs(d, key, 1)
+			if err != nil {
+				return nil, err
+			}
+			exec.OnError = args[0]
+		default:
+			return nil, d.Errf("unsupported %q key", key)
+		}
+	}
+	return exec, nil
+}
+
+// parseCaddyfileArgs returns the remaining args on the current line,
+// erroring out if there are fewer than min.
+func parseCaddyfileArgs(d *caddyfile.Dispenser, name string, min int) ([]string, error) {
+	args := d.RemainingArgs()
+	if len(args) < min {
+		return nil, d.Errf("too few args for %q directive (config: %d, min: %d)", name, len(args), min)
+	}
+	return args, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caddyserver/caddy/v2/caddytest"
+)
+
+// TestCaddyfileAdapt drives each testdata/*.caddyfile through the full
+// Caddy adapter pipeline (caddytest.AssertAdapt) and diffs the produced
+// JSON against its *.json sibling. This catches regressions where the
+// "git" global option parser interacts badly with the rest of the
+// adapter, which TestParseCaddyfileAppConfig alone cannot see.
+func TestCaddyfileAdapt(t *testing.T) {
+	matches, err := filepath.Glob("testdata/*.caddyfile")
+	if err != nil {
+		t.Fatalf("failed to list testdata: %v", err)
+	}
+	if len(matches) == 0 {
+		t.Fatal("no testdata found")
+	}
+	for _, cf := range matches {
+		name := strings.TrimSuffix(filepath.Base(cf), ".caddyfile")
+		t.Run(name, func(t *testing.T) {
+			rawConfig, err := os.ReadFile(cf)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", cf, err)
+			}
+			wantConfig, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+			if err != nil {
+				t.Fatalf("failed to read %s.json: %v", name, err)
+			}
+			caddytest.AssertAdapt(t, string(rawConfig), "caddyfile", string(wantConfig))
+		})
+	}
+}
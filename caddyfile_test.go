@@ -212,6 +212,250 @@ func TestParseCaddyfileAppConfig(t *testing.T) {
                   }
                 ]
               }
+            }`,
+		},
+		{
+			name: "test parse repo config with post pull exec pipeline",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                branch gh-pages
+                post pull exec {
+                  name Pager
+                  command /usr/local/bin/pager
+                  args "pulled {{.Repo}} at {{.NewSHA}}"
+                  timeout 30s
+                  working_dir /tmp/authp.github.io
+                  env GIT_BRANCH={{.Branch}}
+                  on_error continue
+                }
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "post_pull_exec": [
+                      {
+                        "name": "Pager",
+                        "command": "/usr/local/bin/pager",
+                        "args": ["pulled {{.Repo}} at {{.NewSHA}}"],
+                        "timeout": "30s",
+                        "working_dir": "/tmp/authp.github.io",
+                        "env": ["GIT_BRANCH={{.Branch}}"],
+                        "on_error": "continue"
+                      }
+                    ]
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse repo config with webhook event and branch filters",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                branch gh-pages
+                webhook Github X-Hub-Signature-256 foobar {
+                  events push,pull_request
+                  match ref == refs/heads/main
+                }
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "webhooks": [
+                      {
+                        "name": "Github",
+                        "header": "X-Hub-Signature-256",
+                        "secret": "foobar",
+                        "events": ["push", "pull_request"],
+                        "match": "ref == refs/heads/main"
+                      }
+                    ]
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse repo config with shallow clone and sparse checkout",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                branch gh-pages
+                depth 1
+                single_branch true
+                sparse docs
+                sparse assets
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "depth":    1,
+                    "single_branch": true,
+                    "sparse": ["docs", "assets"]
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse ssh config with agent auth",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url git@github.com:authp/authp.github.io.git
+                auth agent
+                branch gh-pages
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "git@github.com:authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "auth": {
+                      "agent": true
+                    }
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse config with github app auth",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                auth github_app app_id 12345 installation_id 67890 private_key /path/to.pem
+                branch gh-pages
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "auth": {
+                      "github_app": {
+                        "app_id": 12345,
+                        "installation_id": 67890,
+                        "private_key": "/path/to.pem"
+                      }
+                    }
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse config with token_from auth indirection",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                auth token_from env:GH_TOKEN
+                branch gh-pages
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "auth": {
+                      "token_from": "env:GH_TOKEN"
+                    }
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse repo config with cron schedule",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                branch gh-pages
+                schedule "*/5 * * * *"
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "schedule": "*/5 * * * *"
+                  }
+                ]
+              }
+            }`,
+		},
+		{
+			name: "test parse repo config with every-duration schedule and jitter",
+			d: caddyfile.NewTestDispenser(`
+            git {
+              repo authp.github.io {
+                base_dir /tmp
+                url https://github.com/authp/authp.github.io.git
+                branch gh-pages
+                schedule @every 2m jitter 30s
+              }
+            }`),
+			want: `{
+              "config": {
+                "repositories": [
+                  {
+                    "address":  "https://github.com/authp/authp.github.io.git",
+                    "base_dir": "/tmp",
+                    "branch":   "gh-pages",
+                    "name":     "authp.github.io",
+                    "schedule": "@every 2m jitter 30s"
+                  }
+                ]
+              }
             }`,
 		},
 		{
@@ -0,0 +1,148 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PostPullExec is a command that is executed after a successful pull of
+// the repository it is attached to.
+type PostPullExec struct {
+	Name       string   `json:"name,omitempty"`
+	Command    string   `json:"command,omitempty"`
+	Args       []string `json:"args,omitempty"`
+	Timeout    string   `json:"timeout,omitempty"`
+	WorkingDir string   `json:"working_dir,omitempty"`
+	// Env holds "KEY=VALUE" entries, consistent with exec.Cmd.Env.
+	Env []string `json:"env,omitempty"`
+	// OnError controls whether the post-pull exec chain continues to
+	// the next entry ("continue", the default) or stops ("abort") when
+	// this command fails.
+	OnError string `json:"on_error,omitempty"`
+
+	timeout time.Duration
+}
+
+// execContext is the data made available to PostPullExec templates in
+// Args and Env, e.g. `{{.Branch}}`.
+type execContext struct {
+	Repo         string
+	Branch       string
+	OldSHA       string
+	NewSHA       string
+	ChangedFiles []string
+}
+
+// Provision parses and validates the exec entry's configuration.
+func (e *PostPullExec) Provision() error {
+	if e.OnError == "" {
+		e.OnError = "continue"
+	}
+	if e.OnError != "continue" && e.OnError != "abort" {
+		return fmt.Errorf("post pull exec %q: invalid on_error %q, must be \"continue\" or \"abort\"", e.Name, e.OnError)
+	}
+	if e.Timeout != "" {
+		d, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			return fmt.Errorf("post pull exec %q: invalid timeout %q: %v", e.Name, e.Timeout, err)
+		}
+		e.timeout = d
+	}
+	return nil
+}
+
+// Run renders the command's args and env against tc and executes it,
+// logging its stdout/stderr to logger.
+func (e *PostPullExec) Run(ctx context.Context, tc execContext, logger *zap.Logger) error {
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	args, err := renderAll(e.Args, tc)
+	if err != nil {
+		return fmt.Errorf("post pull exec %q: %v", e.Name, err)
+	}
+	env, err := renderAll(e.Env, tc)
+	if err != nil {
+		return fmt.Errorf("post pull exec %q: %v", e.Name, err)
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command, args...)
+	cmd.Dir = e.WorkingDir
+	// Extend, rather than replace, the parent environment: cmd.Env is
+	// nil by default, so appending to it directly would otherwise drop
+	// PATH/HOME/etc. as soon as a single `env` entry is configured.
+	cmd.Env = append(os.Environ(), env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if stdout.Len() > 0 {
+		logger.Info("post pull exec stdout", zap.String("name", e.Name), zap.String("output", stdout.String()))
+	}
+	if stderr.Len() > 0 {
+		logger.Warn("post pull exec stderr", zap.String("name", e.Name), zap.String("output", stderr.String()))
+	}
+	if runErr != nil {
+		return fmt.Errorf("post pull exec %q: %v", e.Name, runErr)
+	}
+	return nil
+}
+
+// renderAll applies the execContext template to each of s, returning the
+// expanded strings.
+func renderAll(s []string, tc execContext) ([]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	out := make([]string, len(s))
+	for i, v := range s {
+		rendered, err := render(v, tc)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = rendered
+	}
+	return out, nil
+}
+
+func render(s string, tc execContext) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New("post_pull_exec").Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %q: %v", s, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, tc); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %v", s, err)
+	}
+	return buf.String(), nil
+}
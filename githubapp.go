@@ -0,0 +1,152 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// githubAppTokenTTL is how long a GitHub App installation token is valid
+// for, per GitHub's API. Tokens are refreshed a minute before expiry.
+const githubAppTokenTTL = time.Hour
+
+// GithubAppAuth mints installation access tokens via a GitHub App's
+// private key, for use as the HTTPS password when pulling a repository.
+type GithubAppAuth struct {
+	AppID          int64  `json:"app_id,omitempty"`
+	InstallationID int64  `json:"installation_id,omitempty"`
+	PrivateKeyPath string `json:"private_key,omitempty"`
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Token returns a valid installation access token, minting (or
+// refreshing) one if the cached token has expired or is about to.
+func (g *GithubAppAuth) Token() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Until(g.expiresAt) > time.Minute {
+		return g.token, nil
+	}
+
+	jwt, err := g.signJWT()
+	if err != nil {
+		return "", fmt.Errorf("failed to sign github app jwt: %v", err)
+	}
+	token, expiresAt, err := g.requestInstallationToken(jwt)
+	if err != nil {
+		return "", fmt.Errorf("failed to mint github app installation token: %v", err)
+	}
+	g.token = token
+	g.expiresAt = expiresAt
+	return g.token, nil
+}
+
+// signJWT builds the short-lived RS256 JWT GitHub requires to mint an
+// installation token, per
+// https://docs.github.com/en/apps/creating-github-apps/authenticating-with-a-github-app/generating-a-json-web-token-jwt-for-a-github-app.
+func (g *GithubAppAuth) signJWT() (string, error) {
+	keyPEM, err := os.ReadFile(g.PrivateKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read private key: %v", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return "", fmt.Errorf("failed to decode PEM block in %s", g.PrivateKeyPath)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		k, err2 := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err2 != nil {
+			return "", fmt.Errorf("failed to parse private key: %v", err)
+		}
+		rsaKey, ok := k.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("private key in %s is not an RSA key", g.PrivateKeyPath)
+		}
+		key = rsaKey
+	}
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprintf("%d", g.AppID),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+
+	signingInput := header + "." + payload
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (g *GithubAppAuth) requestInstallationToken(jwt string) (string, time.Time, error) {
+	url := fmt.Sprintf("https://api.github.com/app/installations/%d/access_tokens", g.InstallationID)
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, fmt.Errorf("github returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return "", time.Time{}, err
+	}
+	if out.ExpiresAt.IsZero() {
+		out.ExpiresAt = time.Now().Add(githubAppTokenTTL)
+	}
+	return out.Token, out.ExpiresAt, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+	"go.uber.org/zap"
+)
+
+func init() {
+	caddy.RegisterModule(Handler{})
+}
+
+// Handler is an HTTP handler that receives webhook requests for a
+// repository and, once verified, triggers a pull.
+type Handler struct {
+	// Repo is the name of the repository this handler pulls.
+	Repo string `json:"repo,omitempty"`
+
+	app    *App
+	repo   *Repository
+	logger *zap.Logger
+}
+
+// CaddyModule returns the Caddy module information.
+func (Handler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.git",
+		New: func() caddy.Module { return new(Handler) },
+	}
+}
+
+// Provision sets up the Handler.
+func (h *Handler) Provision(ctx caddy.Context) error {
+	h.logger = ctx.Logger(h)
+	unm, err := ctx.App("git")
+	if err != nil {
+		return err
+	}
+	h.app = unm.(*App)
+	for _, repo := range h.app.Config.Repositories {
+		if repo.Name == h.Repo {
+			h.repo = repo
+			break
+		}
+	}
+	if h.repo == nil {
+		return fmt.Errorf("repo %q not found in git app config", h.Repo)
+	}
+	return nil
+}
+
+// ServeHTTP implements caddyhttp.MiddlewareHandler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return caddyhttp.Error(http.StatusBadRequest, err)
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "" {
+		event = r.Header.Get("X-Gitea-Event")
+	}
+	if event == "" {
+		event = r.Header.Get("X-Gitlab-Event")
+	}
+	if event == "" {
+		event = r.Header.Get("X-Event-Key")
+	}
+
+	var verifyErr error
+	var anyVerified bool
+	matched := len(h.repo.Webhooks) == 0
+	for _, wh := range h.repo.Webhooks {
+		if err := wh.Verify(r, body); err != nil {
+			verifyErr = err
+			continue
+		}
+		anyVerified = true
+		if !wh.MatchesEvent(event) || !wh.MatchesPayload(body) {
+			continue
+		}
+		matched = true
+		break
+	}
+	if verifyErr != nil && !anyVerified {
+		return caddyhttp.Error(http.StatusUnauthorized, verifyErr)
+	}
+	if !matched {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	if err := h.repo.Pull(); err != nil {
+		h.logger.Error("webhook-triggered pull failed", zap.String("repo", h.repo.Name), zap.Error(err))
+		return caddyhttp.Error(http.StatusInternalServerError, err)
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+var (
+	_ caddy.Provisioner           = (*Handler)(nil)
+	_ caddyhttp.MiddlewareHandler = (*Handler)(nil)
+)
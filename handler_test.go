@@ -0,0 +1,53 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestHandlerServeHTTPIgnoresUnrelatedWebhookFailure reproduces a request
+// that is correctly signed for one webhook but filtered out by its event
+// list, alongside a second, unrelated webhook whose signature does not
+// match. The request must be filtered (204), not rejected (401) on
+// account of the unrelated webhook's failure.
+func TestHandlerServeHTTPIgnoresUnrelatedWebhookFailure(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	h := &Handler{
+		repo: &Repository{
+			Name: "test",
+			Webhooks: []*Webhook{
+				{Name: "Github", Secret: "s3cr3t", Events: []string{"pull_request"}},
+				{Name: "Gitlab", Secret: "other-secret"},
+			},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", signGithub("s3cr3t", body))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-Gitlab-Token", "wrong-token")
+
+	w := httptest.NewRecorder()
+	if err := h.ServeHTTP(w, req, nil); err != nil {
+		t.Fatalf("ServeHTTP returned an error: %v", err)
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected %d for a filtered-but-verified event, got %d", http.StatusNoContent, w.Code)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// Repository represents a single git repository managed by the app.
+type Repository struct {
+	Name         string          `json:"name,omitempty"`
+	Address      string          `json:"address,omitempty"`
+	BaseDir      string          `json:"base_dir,omitempty"`
+	Branch       string          `json:"branch,omitempty"`
+	Force        bool            `json:"force,omitempty"`
+	Depth        int             `json:"depth,omitempty"`
+	SingleBranch bool            `json:"single_branch,omitempty"`
+	Sparse       []string        `json:"sparse,omitempty"`
+	Webhooks     []*Webhook      `json:"webhooks,omitempty"`
+	PostPullExec []*PostPullExec `json:"post_pull_exec,omitempty"`
+	Auth         *AuthConfig     `json:"auth,omitempty"`
+	// Schedule is a cron expression, e.g. `*/5 * * * *` or
+	// `@every 2m jitter 30s`, on which the repository is pulled
+	// automatically.
+	Schedule string `json:"schedule,omitempty"`
+
+	dir          string
+	logger       *zap.Logger
+	mu           sync.Mutex
+	cronSchedule cron.Schedule
+	jitter       time.Duration
+	nextRunMu    sync.Mutex
+	nextRun      time.Time
+}
+
+// Provision sets up the repository runtime state.
+func (r *Repository) Provision(logger *zap.Logger) error {
+	r.logger = logger
+	if r.Name == "" {
+		return fmt.Errorf("repository name not set")
+	}
+	if r.Address == "" {
+		return fmt.Errorf("repository %q: address not set", r.Name)
+	}
+	if r.BaseDir == "" {
+		return fmt.Errorf("repository %q: base_dir not set", r.Name)
+	}
+	r.dir = filepath.Join(r.BaseDir, r.Name)
+	for _, e := range r.PostPullExec {
+		if err := e.Provision(); err != nil {
+			return fmt.Errorf("repository %q: %v", r.Name, err)
+		}
+	}
+	if err := r.provisionSchedule(); err != nil {
+		return fmt.Errorf("repository %q: %v", r.Name, err)
+	}
+	return nil
+}
+
+// provisionSchedule parses the Schedule directive, which is a standard
+// 5-field cron expression or `@every` descriptor, optionally followed by
+// `jitter <duration>`.
+func (r *Repository) provisionSchedule() error {
+	if r.Schedule == "" {
+		return nil
+	}
+	spec := r.Schedule
+	var jitter time.Duration
+	if idx := strings.Index(spec, " jitter "); idx != -1 {
+		jitterSpec := strings.TrimSpace(spec[idx+len(" jitter "):])
+		d, err := time.ParseDuration(jitterSpec)
+		if err != nil {
+			return fmt.Errorf("invalid jitter %q: %v", jitterSpec, err)
+		}
+		jitter = d
+		spec = strings.TrimSpace(spec[:idx])
+	}
+
+	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	sched, err := parser.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+	r.cronSchedule = sched
+	r.jitter = jitter
+	return nil
+}
+
+// setNextRun records the time of the repository's next scheduled pull.
+func (r *Repository) setNextRun(t time.Time) {
+	r.nextRunMu.Lock()
+	r.nextRun = t
+	r.nextRunMu.Unlock()
+}
+
+// NextRun returns the time of the repository's next scheduled pull, and
+// whether a schedule is configured at all.
+func (r *Repository) NextRun() (time.Time, bool) {
+	r.nextRunMu.Lock()
+	defer r.nextRunMu.Unlock()
+	return r.nextRun, r.cronSchedule != nil
+}
+
+// Validate validates the repository configuration.
+func (r *Repository) Validate() error {
+	if r.Depth < 0 {
+		return fmt.Errorf("repository %q: depth must not be negative", r.Name)
+	}
+	return nil
+}
+
+// Start clones the repository if it has not been cloned yet.
+func (r *Repository) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := os.Stat(r.dir); os.IsNotExist(err) {
+		return r.clone()
+	}
+	return nil
+}
+
+// Stop releases repository resources.
+func (r *Repository) Stop() {}
+
+func (r *Repository) cloneOptions() (*git.CloneOptions, error) {
+	auth, err := r.Auth.method()
+	if err != nil {
+		return nil, fmt.Errorf("repository %q: %v", r.Name, err)
+	}
+	opts := &git.CloneOptions{
+		URL:          r.Address,
+		Auth:         auth,
+		Depth:        r.Depth,
+		SingleBranch: r.SingleBranch,
+	}
+	if r.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(r.Branch)
+	}
+	return opts, nil
+}
+
+func (r *Repository) clone() error {
+	opts, err := r.cloneOptions()
+	if err != nil {
+		return err
+	}
+	if _, err := git.PlainClone(r.dir, false, opts); err != nil {
+		return fmt.Errorf("repository %q: failed to clone: %v", r.Name, err)
+	}
+	if len(r.Sparse) > 0 {
+		if err := sparseCheckout(r.dir, r.Sparse); err != nil {
+			return fmt.Errorf("repository %q: failed to apply sparse checkout: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// sparseCheckout restricts the repository's worktree to the given path
+// patterns, so that only a subset of a large monorepo is materialized
+// on disk. go-git v5.4.2's CheckoutOptions predates sparse-checkout
+// support, so this shells out to the system `git` binary instead, the
+// same way `git sparse-checkout set` itself works under the hood:
+// writing `.git/info/sparse-checkout` and re-reading the tree.
+func sparseCheckout(dir string, patterns []string) error {
+	infoDir := filepath.Join(dir, ".git", "info")
+	if err := os.MkdirAll(infoDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %v", infoDir, err)
+	}
+	content := strings.Join(patterns, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(infoDir, "sparse-checkout"), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write sparse-checkout patterns: %v", err)
+	}
+	if err := runGit(dir, "config", "core.sparseCheckout", "true"); err != nil {
+		return err
+	}
+	return runGit(dir, "read-tree", "-mu", "HEAD")
+}
+
+// runGit runs the system `git` binary in dir, returning its combined
+// output on failure for diagnostics.
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Pull fetches and merges upstream changes into the local clone, then
+// runs the configured post-pull exec chain.
+func (r *Repository) Pull() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	repo, err := git.PlainOpen(r.dir)
+	if err != nil {
+		return fmt.Errorf("repository %q: failed to open: %v", r.Name, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("repository %q: failed to get worktree: %v", r.Name, err)
+	}
+	oldHead, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("repository %q: failed to resolve HEAD: %v", r.Name, err)
+	}
+	oldSHA := oldHead.Hash()
+
+	auth, err := r.Auth.method()
+	if err != nil {
+		return fmt.Errorf("repository %q: %v", r.Name, err)
+	}
+	opts := &git.PullOptions{
+		Auth:         auth,
+		Force:        r.Force,
+		SingleBranch: r.SingleBranch,
+		Depth:        r.Depth,
+	}
+	if r.Branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(r.Branch)
+	}
+	if err := wt.Pull(opts); err != nil {
+		if err == git.NoErrAlreadyUpToDate {
+			return nil
+		}
+		return fmt.Errorf("repository %q: failed to pull: %v", r.Name, err)
+	}
+
+	newHead, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("repository %q: failed to resolve new HEAD: %v", r.Name, err)
+	}
+	changedFiles, err := changedFilesBetween(repo, oldSHA, newHead.Hash())
+	if err != nil {
+		return fmt.Errorf("repository %q: failed to diff pulled changes: %v", r.Name, err)
+	}
+
+	return r.runPostPullExec(execContext{
+		Repo:         r.Name,
+		Branch:       r.Branch,
+		OldSHA:       oldSHA.String(),
+		NewSHA:       newHead.Hash().String(),
+		ChangedFiles: changedFiles,
+	})
+}
+
+// runPostPullExec runs the repository's post-pull exec chain in order,
+// stopping early if an entry fails and its OnError is "abort".
+func (r *Repository) runPostPullExec(tc execContext) error {
+	for _, e := range r.PostPullExec {
+		if err := e.Run(context.Background(), tc, r.logger); err != nil {
+			r.logger.Error("post pull exec failed", zap.String("repo", r.Name), zap.String("name", e.Name), zap.Error(err))
+			if e.OnError == "abort" {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// changedFilesBetween returns the paths that differ between two commits.
+func changedFilesBetween(repo *git.Repository, oldSHA, newSHA plumbing.Hash) ([]string, error) {
+	oldCommit, err := repo.CommitObject(oldSHA)
+	if err != nil {
+		return nil, err
+	}
+	newCommit, err := repo.CommitObject(newSHA)
+	if err != nil {
+		return nil, err
+	}
+	oldTree, err := oldCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := newCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := oldTree.Diff(newTree)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, c := range changes {
+		name := c.To.Name
+		if name == "" {
+			// Deletions carry a zero-value To entry; fall back to the
+			// source path so deleted files are still reported.
+			name = c.From.Name
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
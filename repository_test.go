@@ -0,0 +1,41 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import "testing"
+
+func TestRepositoryProvisionInvalidSchedule(t *testing.T) {
+	r := &Repository{
+		Name:     "test",
+		Address:  "https://example.com/test.git",
+		BaseDir:  "/tmp",
+		Schedule: "not a cron expression",
+	}
+	if err := r.Provision(nil); err == nil {
+		t.Fatal("expected error for invalid cron schedule, got nil")
+	}
+}
+
+func TestRepositoryProvisionInvalidJitter(t *testing.T) {
+	r := &Repository{
+		Name:     "test",
+		Address:  "https://example.com/test.git",
+		BaseDir:  "/tmp",
+		Schedule: "@every 2m jitter not-a-duration",
+	}
+	if err := r.Provision(nil); err == nil {
+		t.Fatal("expected error for invalid jitter duration, got nil")
+	}
+}
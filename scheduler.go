@@ -0,0 +1,92 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// scheduler runs scheduled pulls for every repository that carries a
+// `schedule` directive, one goroutine per repository. A scheduled pull
+// shares Repository.Pull with webhook-triggered pulls, so the
+// repository's own mutex coalesces the two: whichever arrives second
+// simply waits its turn rather than running concurrently.
+type scheduler struct {
+	logger *zap.Logger
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+}
+
+func newScheduler(logger *zap.Logger) *scheduler {
+	return &scheduler{logger: logger}
+}
+
+// Start launches one goroutine per scheduled repository.
+func (s *scheduler) Start(repos []*Repository) {
+	for _, repo := range repos {
+		if repo.cronSchedule == nil {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		s.mu.Lock()
+		s.cancels = append(s.cancels, cancel)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.run(ctx, repo)
+	}
+}
+
+// Stop cancels every scheduled repository's goroutine and waits for them
+// to exit, so Stop blocks until no scheduled pull is still in flight.
+func (s *scheduler) Stop() {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.cancels = nil
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *scheduler) run(ctx context.Context, repo *Repository) {
+	defer s.wg.Done()
+	for {
+		next := repo.cronSchedule.Next(time.Now())
+		if repo.jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(repo.jitter))))
+		}
+		repo.setNextRun(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := repo.Pull(); err != nil {
+			s.logger.Error("scheduled pull failed", zap.String("repo", repo.Name), zap.Error(err))
+		}
+	}
+}
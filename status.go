@@ -0,0 +1,80 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/modules/caddyhttp"
+)
+
+func init() {
+	caddy.RegisterModule(StatusHandler{})
+}
+
+// StatusHandler is an HTTP handler that reports each configured
+// repository's next scheduled pull time as JSON.
+type StatusHandler struct {
+	app *App
+}
+
+// repoStatus is the JSON representation of a single repository's status.
+type repoStatus struct {
+	Name     string     `json:"name"`
+	Branch   string     `json:"branch,omitempty"`
+	Schedule string     `json:"schedule,omitempty"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+}
+
+// CaddyModule returns the Caddy module information.
+func (StatusHandler) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "http.handlers.git_status",
+		New: func() caddy.Module { return new(StatusHandler) },
+	}
+}
+
+// Provision sets up the StatusHandler.
+func (h *StatusHandler) Provision(ctx caddy.Context) error {
+	unm, err := ctx.App("git")
+	if err != nil {
+		return err
+	}
+	h.app = unm.(*App)
+	return nil
+}
+
+// ServeHTTP implements caddyhttp.MiddlewareHandler.
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request, next caddyhttp.Handler) error {
+	statuses := make([]repoStatus, 0, len(h.app.Config.Repositories))
+	for _, repo := range h.app.Config.Repositories {
+		s := repoStatus{Name: repo.Name, Branch: repo.Branch, Schedule: repo.Schedule}
+		if next, ok := repo.NextRun(); ok {
+			s.NextRun = &next
+		}
+		statuses = append(statuses, s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(statuses)
+}
+
+var (
+	_ caddy.Provisioner           = (*StatusHandler)(nil)
+	_ caddyhttp.MiddlewareHandler = (*StatusHandler)(nil)
+)
@@ -0,0 +1,198 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Webhook is a webhook that, when invoked, triggers a pull of the
+// repository it is attached to.
+type Webhook struct {
+	Name   string `json:"name,omitempty"`
+	Header string `json:"header,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	// Events restricts the webhook to the listed event types, e.g.
+	// "push", "pull_request". When empty, all events trigger a pull.
+	Events []string `json:"events,omitempty"`
+	// Allow restricts the webhook to the listed source IPs or CIDRs.
+	// Currently only honored for Bitbucket, which does not sign requests.
+	Allow []string `json:"allow,omitempty"`
+	// Match is a simple `<json path> == <value>` expression evaluated
+	// against the webhook payload, e.g. `ref == refs/heads/main`, so
+	// that a webhook only triggers a pull when the branch matches.
+	Match string `json:"match,omitempty"`
+}
+
+// Verify validates an inbound webhook request against the configuration,
+// returning an error if the request should be rejected.
+func (w *Webhook) Verify(r *http.Request, body []byte) error {
+	switch w.Name {
+	case "Github":
+		return w.verifyGithub(r, body)
+	case "Gitlab":
+		return w.verifyGitlab(r)
+	case "Gitea":
+		return w.verifyGitea(r, body)
+	case "Bitbucket":
+		return w.verifyBitbucket(r)
+	default:
+		return w.verifyGeneric(r, body)
+	}
+}
+
+func (w *Webhook) verifyGithub(r *http.Request, body []byte) error {
+	sig := r.Header.Get(w.header("X-Hub-Signature-256"))
+	const prefix = "sha256="
+	if !strings.HasPrefix(sig, prefix) {
+		return fmt.Errorf("webhook %q: missing or malformed %s header", w.Name, w.header("X-Hub-Signature-256"))
+	}
+	if !hmacEqual(w.Secret, body, strings.TrimPrefix(sig, prefix)) {
+		return fmt.Errorf("webhook %q: signature mismatch", w.Name)
+	}
+	return nil
+}
+
+func (w *Webhook) verifyGitlab(r *http.Request) error {
+	token := r.Header.Get(w.header("X-Gitlab-Token"))
+	if subtle.ConstantTimeCompare([]byte(token), []byte(w.Secret)) != 1 {
+		return fmt.Errorf("webhook %q: token mismatch", w.Name)
+	}
+	return nil
+}
+
+func (w *Webhook) verifyGitea(r *http.Request, body []byte) error {
+	if sig := r.Header.Get(w.header("X-Gitea-Signature")); sig != "" {
+		if !hmacEqual(w.Secret, body, sig) {
+			return fmt.Errorf("webhook %q: signature mismatch", w.Name)
+		}
+		return nil
+	}
+	return w.verifyGeneric(r, body)
+}
+
+func (w *Webhook) verifyBitbucket(r *http.Request) error {
+	if len(w.Allow) == 0 {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("webhook %q: failed to parse remote address %q", w.Name, r.RemoteAddr)
+	}
+	for _, allowed := range w.Allow {
+		if _, cidr, err := net.ParseCIDR(allowed); err == nil {
+			if cidr.Contains(ip) {
+				return nil
+			}
+			continue
+		}
+		if allowed == host {
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook %q: remote address %q not allowed", w.Name, host)
+}
+
+func (w *Webhook) verifyGeneric(r *http.Request, body []byte) error {
+	if w.Header == "" {
+		return nil
+	}
+	got := r.Header.Get(w.Header)
+	if got == w.Secret {
+		return nil
+	}
+	if hmacEqual(w.Secret, body, got) {
+		return nil
+	}
+	return fmt.Errorf("webhook %q: token mismatch", w.Name)
+}
+
+// header returns the configured header name, falling back to def when
+// the Caddyfile did not set one explicitly.
+func (w *Webhook) header(def string) string {
+	if w.Header != "" {
+		return w.Header
+	}
+	return def
+}
+
+// hmacEqual reports whether hexSig is the hex-encoded HMAC-SHA256 of
+// body keyed with secret, compared in constant time.
+func hmacEqual(secret string, body []byte, hexSig string) bool {
+	sig, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+// MatchesEvent reports whether the webhook is configured to react to the
+// given event name. An unconfigured Events list matches every event.
+func (w *Webhook) MatchesEvent(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if strings.EqualFold(e, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesPayload evaluates the configured Match expression, e.g.
+// `ref == refs/heads/main`, against the decoded JSON payload. An
+// unconfigured expression always matches.
+func (w *Webhook) MatchesPayload(body []byte) bool {
+	if w.Match == "" {
+		return true
+	}
+	parts := strings.SplitN(w.Match, "==", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	path := strings.TrimSpace(parts[0])
+	want := strings.TrimSpace(parts[1])
+
+	var got interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		return false
+	}
+	// Walk dot-separated path segments, e.g. `repository.name`.
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := got.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if got, ok = obj[key]; !ok {
+			return false
+		}
+	}
+	return fmt.Sprintf("%v", got) == want
+}
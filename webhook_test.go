@@ -0,0 +1,89 @@
+// Copyright 2022 Paul Greenberg greenpau@outlook.com
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func signGithub(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookVerifyGithub(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/main"}`)
+	wh := &Webhook{Name: "Github", Secret: "s3cr3t"}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Hub-Signature-256", signGithub("s3cr3t", body))
+	if err := wh.Verify(req, body); err != nil {
+		t.Fatalf("expected a correctly signed request to verify, got: %v", err)
+	}
+
+	bad := httptest.NewRequest(http.MethodPost, "/", nil)
+	bad.Header.Set("X-Hub-Signature-256", signGithub("wrong", body))
+	if err := wh.Verify(bad, body); err == nil {
+		t.Fatal("expected a mismatched signature to fail verification")
+	}
+}
+
+func TestWebhookVerifyGitlab(t *testing.T) {
+	wh := &Webhook{Name: "Gitlab", Secret: "s3cr3t"}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Gitlab-Token", "s3cr3t")
+	if err := wh.Verify(req, nil); err != nil {
+		t.Fatalf("expected a matching token to verify, got: %v", err)
+	}
+
+	bad := httptest.NewRequest(http.MethodPost, "/", nil)
+	bad.Header.Set("X-Gitlab-Token", "wrong")
+	if err := wh.Verify(bad, nil); err == nil {
+		t.Fatal("expected a mismatched token to fail verification")
+	}
+}
+
+func TestWebhookMatchesEvent(t *testing.T) {
+	wh := &Webhook{Events: []string{"push", "pull_request"}}
+	if !wh.MatchesEvent("push") {
+		t.Error("expected \"push\" to match the configured events")
+	}
+	if wh.MatchesEvent("issues") {
+		t.Error("expected \"issues\" not to match the configured events")
+	}
+	if (&Webhook{}).MatchesEvent("anything") != true {
+		t.Error("expected an unconfigured Events list to match every event")
+	}
+}
+
+func TestWebhookMatchesPayload(t *testing.T) {
+	wh := &Webhook{Match: "ref == refs/heads/main"}
+	if !wh.MatchesPayload([]byte(`{"ref":"refs/heads/main"}`)) {
+		t.Error("expected a matching ref to satisfy the Match expression")
+	}
+	if wh.MatchesPayload([]byte(`{"ref":"refs/heads/dev"}`)) {
+		t.Error("expected a non-matching ref not to satisfy the Match expression")
+	}
+	if !(&Webhook{}).MatchesPayload([]byte(`{}`)) {
+		t.Error("expected an unconfigured Match expression to always match")
+	}
+}